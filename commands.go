@@ -0,0 +1,284 @@
+package main
+
+import (
+	"github.com/bwmarrin/discordgo"
+)
+
+// CommandContext carries the invocation details a Command needs beyond the
+// Client itself, so the same Command works whether it was invoked via a
+// prefix message or a Discord slash command.
+type CommandContext struct {
+	Session *discordgo.Session
+	Guild   *discordgo.Guild
+	UserID  string
+}
+
+// Command is implemented by every command the bot supports. A single table
+// of Commands backs both prefix-based invocation (e.g. "!play ...") and
+// slash commands registered as discordgo ApplicationCommands.
+type Command interface {
+	Name() string
+	Description() string
+	// Options describes this command's slash command arguments. Returns nil
+	// for commands that take none.
+	Options() []*discordgo.ApplicationCommandOption
+	Run(ctx *CommandContext, c *Client, args []string)
+}
+
+// commandRegistry lists every command the bot supports. Order only affects
+// the order commands are registered as slash commands in.
+var commandRegistry = []Command{
+	helpCommand{},
+	playCommand{},
+	seekCommand{},
+	posCommand{},
+	loopCommand{},
+	addCommand{},
+	queueCommand{},
+	pauseCommand{},
+	stopCommand{},
+	skipCommand{},
+	voteSkipCommand{},
+	forceSkipCommand{},
+	voteStopCommand{},
+	deleteCommand{},
+	swapCommand{},
+	shuffleCommand{},
+}
+
+// commandsByName indexes commandRegistry by Name() for dispatch.
+var commandsByName = func() map[string]Command {
+	m := make(map[string]Command, len(commandRegistry))
+	for _, cmd := range commandRegistry {
+		m[cmd.Name()] = cmd
+	}
+	return m
+}()
+
+type helpCommand struct{}
+
+func (helpCommand) Name() string        { return "help" }
+func (helpCommand) Description() string { return "Show the list of available commands." }
+func (helpCommand) Options() []*discordgo.ApplicationCommandOption { return nil }
+func (helpCommand) Run(ctx *CommandContext, c *Client, args []string) {
+	commandHelp(c)
+}
+
+type playCommand struct{}
+
+func (playCommand) Name() string        { return "play" }
+func (playCommand) Description() string { return "Play a track, or resume playback if paused." }
+func (playCommand) Options() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        "query",
+			Description: "URL of the track to play.",
+		},
+	}
+}
+func (playCommand) Run(ctx *CommandContext, c *Client, args []string) {
+	voiceChannelID := c.GetVoiceChannelID()
+	if voiceChannelID == "" {
+		c.Messagef("You need to be in a voice channel to play something.")
+		return
+	}
+
+	if len(args) == 0 {
+		if p, ok := c.GetPlaybackInfo(); ok && p.Paused {
+			commandPause(c)
+			return
+		}
+		c.Messagef("Usage: %splay <url>", c.EffectivePrefix())
+		return
+	}
+	query := args[0]
+
+	// Enqueueing and returning immediately keeps a noisy guild's playback
+	// requests from blocking command handling for any other guild; the
+	// per-guild worker goroutine serializes the actual voice-channel join
+	// and playback.
+	Enqueue(ctx.Session, &Play{
+		GuildID:   c.GuildID,
+		ChannelID: voiceChannelID,
+		UserID:    ctx.UserID,
+		Track:     Track{Url: query, RequesterID: ctx.UserID},
+	})
+
+	// Reply synchronously, before Run returns, so a slash command's
+	// deferred ack always resolves; the track itself plays once the worker
+	// goroutine gets to it and sends its own Now Playing embed.
+	c.Messagef("Queued up.")
+}
+
+type seekCommand struct{}
+
+func (seekCommand) Name() string        { return "seek" }
+func (seekCommand) Description() string { return "Seek to a position in the current track." }
+func (seekCommand) Options() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        "position",
+			Description: "Position to seek to, e.g. 1m30s.",
+			Required:    true,
+		},
+	}
+}
+func (seekCommand) Run(ctx *CommandContext, c *Client, args []string) {
+	commandSeek(c, args)
+}
+
+type posCommand struct{}
+
+func (posCommand) Name() string        { return "pos" }
+func (posCommand) Description() string { return "Show the current playback position." }
+func (posCommand) Options() []*discordgo.ApplicationCommandOption { return nil }
+func (posCommand) Run(ctx *CommandContext, c *Client, args []string) {
+	if t, ok := c.GetPlaybackInfo(); ok {
+		c.SendNowPlaying(t.Track)
+		return
+	}
+	commandPos(c)
+}
+
+type loopCommand struct{}
+
+func (loopCommand) Name() string        { return "loop" }
+func (loopCommand) Description() string { return "Toggle looping of the current track." }
+func (loopCommand) Options() []*discordgo.ApplicationCommandOption { return nil }
+func (loopCommand) Run(ctx *CommandContext, c *Client, args []string) {
+	commandLoop(c)
+}
+
+type addCommand struct{}
+
+func (addCommand) Name() string        { return "add" }
+func (addCommand) Description() string { return "Add a track to the back of the queue." }
+func (addCommand) Options() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        "query",
+			Description: "URL of the track to add.",
+			Required:    true,
+		},
+	}
+}
+func (addCommand) Run(ctx *CommandContext, c *Client, args []string) {
+	commandAdd(c, args, false)
+}
+
+type queueCommand struct{}
+
+func (queueCommand) Name() string        { return "queue" }
+func (queueCommand) Description() string { return "Show the current queue." }
+func (queueCommand) Options() []*discordgo.ApplicationCommandOption { return nil }
+func (queueCommand) Run(ctx *CommandContext, c *Client, args []string) {
+	c.SendQueue()
+}
+
+type pauseCommand struct{}
+
+func (pauseCommand) Name() string        { return "pause" }
+func (pauseCommand) Description() string { return "Pause or unpause playback." }
+func (pauseCommand) Options() []*discordgo.ApplicationCommandOption { return nil }
+func (pauseCommand) Run(ctx *CommandContext, c *Client, args []string) {
+	commandPause(c)
+}
+
+type stopCommand struct{}
+
+func (stopCommand) Name() string        { return "stop" }
+func (stopCommand) Description() string { return "Stop playback and clear the queue." }
+func (stopCommand) Options() []*discordgo.ApplicationCommandOption { return nil }
+func (stopCommand) Run(ctx *CommandContext, c *Client, args []string) {
+	commandStop(c)
+}
+
+type skipCommand struct{}
+
+func (skipCommand) Name() string        { return "skip" }
+func (skipCommand) Description() string { return "Skip the current track." }
+func (skipCommand) Options() []*discordgo.ApplicationCommandOption { return nil }
+func (skipCommand) Run(ctx *CommandContext, c *Client, args []string) {
+	commandSkip(c)
+}
+
+type voteSkipCommand struct{}
+
+func (voteSkipCommand) Name() string        { return "voteskip" }
+func (voteSkipCommand) Description() string { return "Start or join a vote to skip the current track." }
+func (voteSkipCommand) Options() []*discordgo.ApplicationCommandOption { return nil }
+func (voteSkipCommand) Run(ctx *CommandContext, c *Client, args []string) {
+	commandVoteSkip(ctx.Guild, c, ctx.UserID)
+}
+
+type forceSkipCommand struct{}
+
+func (forceSkipCommand) Name() string        { return "forceskip" }
+func (forceSkipCommand) Description() string { return "(Admin/DJ) Immediately skip the current track." }
+func (forceSkipCommand) Options() []*discordgo.ApplicationCommandOption { return nil }
+func (forceSkipCommand) Run(ctx *CommandContext, c *Client, args []string) {
+	commandForceSkip(ctx.Session, ctx.Guild, c, ctx.UserID)
+}
+
+type voteStopCommand struct{}
+
+func (voteStopCommand) Name() string        { return "votestop" }
+func (voteStopCommand) Description() string { return "Start or join a vote to stop playback." }
+func (voteStopCommand) Options() []*discordgo.ApplicationCommandOption { return nil }
+func (voteStopCommand) Run(ctx *CommandContext, c *Client, args []string) {
+	commandVoteStop(ctx.Guild, c, ctx.UserID)
+}
+
+type deleteCommand struct{}
+
+func (deleteCommand) Name() string        { return "delete" }
+func (deleteCommand) Description() string { return "Delete a track from the queue." }
+func (deleteCommand) Options() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionInteger,
+			Name:        "index",
+			Description: "Queue position of the track to delete.",
+			Required:    true,
+		},
+	}
+}
+func (deleteCommand) Run(ctx *CommandContext, c *Client, args []string) {
+	commandDelete(c, args)
+}
+
+type swapCommand struct{}
+
+func (swapCommand) Name() string        { return "swap" }
+func (swapCommand) Description() string { return "Swap the positions of two tracks in the queue." }
+func (swapCommand) Options() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionInteger,
+			Name:        "a",
+			Description: "First queue position.",
+			Required:    true,
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionInteger,
+			Name:        "b",
+			Description: "Second queue position.",
+			Required:    true,
+		},
+	}
+}
+func (swapCommand) Run(ctx *CommandContext, c *Client, args []string) {
+	commandSwap(c, args)
+}
+
+type shuffleCommand struct{}
+
+func (shuffleCommand) Name() string        { return "shuffle" }
+func (shuffleCommand) Description() string { return "Shuffle the queue." }
+func (shuffleCommand) Options() []*discordgo.ApplicationCommandOption { return nil }
+func (shuffleCommand) Run(ctx *CommandContext, c *Client, args []string) {
+	commandShuffle(c)
+}
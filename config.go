@@ -7,10 +7,11 @@ import (
 )
 
 type Config struct {
-	Prefix     string `json:"prefix"`
-	Token      string `json:"token"`
-	YtdlPath   string `json:"youtube-dl_path"`
-	FfmpegPath string `json:"ffmpeg_path"`
+	Prefix             string `json:"prefix"`
+	Token              string `json:"token"`
+	FfmpegPath         string `json:"ffmpeg_path"`
+	StoragePath        string `json:"storage_path"`
+	SoundCloudClientID string `json:"soundcloud_client_id"`
 }
 
 const configFile = "config.json"
@@ -32,10 +33,10 @@ func ReadConfig(cfg *Config) error {
 
 func WriteDefaultConfig() error {
 	data, err := json.MarshalIndent(Config{
-		Prefix:     "!",
-		Token:      tokenDefaultString,
-		YtdlPath:   "youtube-dl",
-		FfmpegPath: "ffmpeg",
+		Prefix:      "!",
+		Token:       tokenDefaultString,
+		FfmpegPath:  "ffmpeg",
+		StoragePath: "musicbot.db",
 	}, "", "\t")
 	if err != nil {
 		return err
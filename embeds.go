@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/hako/durafmt"
+)
+
+// sendEmbed sends embed through the active interaction followup, if any,
+// otherwise to the guild's text channel. It returns the sent message, or nil
+// if there was nowhere to send it or sending failed.
+func (c *Client) sendEmbed(embed *discordgo.MessageEmbed) *discordgo.Message {
+	c.RLock()
+	i := c.Interaction
+	textChannelID := c.TextChannelID
+	c.RUnlock()
+
+	if i != nil {
+		msg, err := c.s.FollowupMessageCreate(i, true, &discordgo.WebhookParams{
+			Embeds: []*discordgo.MessageEmbed{embed},
+		})
+		if err != nil {
+			return nil
+		}
+		return msg
+	}
+
+	if textChannelID == "" {
+		return nil
+	}
+	msg, err := c.s.ChannelMessageSendEmbed(textChannelID, embed)
+	if err != nil {
+		return nil
+	}
+	return msg
+}
+
+// SendNowPlaying sends the "Now Playing" embed for t, editing the
+// previously sent one in place rather than spamming a new message as long as
+// playback stays within the same text channel.
+func (c *Client) SendNowPlaying(t Track) {
+	embed := c.nowPlayingEmbed(t)
+
+	c.RLock()
+	i := c.Interaction
+	textChannelID := c.TextChannelID
+	msgID := c.nowPlayingMsgID
+	c.RUnlock()
+
+	if i == nil && textChannelID != "" && msgID != "" {
+		if _, err := c.s.ChannelMessageEditEmbed(textChannelID, msgID, embed); err == nil {
+			return
+		}
+	}
+
+	msg := c.sendEmbed(embed)
+	if msg == nil {
+		return
+	}
+	c.Lock()
+	c.nowPlayingMsgID = msg.ID
+	c.Unlock()
+}
+
+func (c *Client) nowPlayingEmbed(t Track) *discordgo.MessageEmbed {
+	p, _ := c.GetPlaybackInfo()
+
+	fields := []*discordgo.MessageEmbedField{
+		{Name: "Requested by", Value: fmt.Sprintf("<@%s>", t.RequesterID), Inline: true},
+	}
+	if t.Duration > 0 {
+		pos := durafmt.Parse(p.Position).LimitFirstN(2).String()
+		dur := durafmt.Parse(t.Duration).LimitFirstN(2).String()
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name: "Position", Value: fmt.Sprintf("%s / %s", pos, dur), Inline: true,
+		})
+	}
+	if p.Paused {
+		fields = append(fields, &discordgo.MessageEmbedField{Name: "Paused", Value: "Yes", Inline: true})
+	}
+	if p.Loop {
+		fields = append(fields, &discordgo.MessageEmbedField{Name: "Looping", Value: "Yes", Inline: true})
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:  "Now Playing: " + t.Title,
+		URL:    t.Url,
+		Author: &discordgo.MessageEmbedAuthor{Name: t.Uploader},
+		Fields: fields,
+	}
+	if t.Thumbnail != "" {
+		embed.Thumbnail = &discordgo.MessageEmbedThumbnail{URL: t.Thumbnail}
+	}
+	return embed
+}
+
+// SendQueue sends an embed listing the tracks waiting to play.
+func (c *Client) SendQueue() {
+	c.RLock()
+	tracks := make([]*Track, len(c.Queue))
+	copy(tracks, c.Queue)
+	c.RUnlock()
+
+	if len(tracks) == 0 {
+		c.sendEmbed(&discordgo.MessageEmbed{
+			Title:       "Queue",
+			Description: "The queue is empty.",
+		})
+		return
+	}
+
+	var sb strings.Builder
+	for i, t := range tracks {
+		fmt.Fprintf(&sb, "**%d.** [%s](%s)", i+1, t.Title, t.Url)
+		if t.Duration > 0 {
+			fmt.Fprintf(&sb, " (%s)", durafmt.Parse(t.Duration).LimitFirstN(2))
+		}
+		fmt.Fprintf(&sb, " — requested by <@%s>\n", t.RequesterID)
+	}
+
+	c.sendEmbed(&discordgo.MessageEmbed{
+		Title:       "Queue",
+		Description: sb.String(),
+	})
+}
@@ -0,0 +1,41 @@
+// Package guild holds per-guild configuration overrides and the subset of
+// bot state that gets persisted across restarts.
+package guild
+
+import "time"
+
+// Config holds per-guild overrides of the bot's global configuration. The
+// zero value of a field means "use the global default".
+type Config struct {
+	Prefix   string `json:"prefix,omitempty"`
+	DJRoleID string `json:"dj_role_id,omitempty"`
+}
+
+// TrackState is the persisted subset of a track needed to restore the queue
+// and resume playback after a restart.
+type TrackState struct {
+	Title       string        `json:"title"`
+	Url         string        `json:"url"`
+	MediaUrl    string        `json:"media_url"`
+	RequesterID string        `json:"requester_id"`
+	Duration    time.Duration `json:"duration"`
+	Thumbnail   string        `json:"thumbnail,omitempty"`
+	Uploader    string        `json:"uploader,omitempty"`
+}
+
+// State is everything about a guild's client that is persisted across
+// restarts.
+type State struct {
+	Config Config `json:"config"`
+
+	TextChannelID  string `json:"text_channel_id"`
+	VoiceChannelID string `json:"voice_channel_id"`
+	Loop           bool   `json:"loop"`
+
+	// NowPlaying and Position describe the track that was interrupted by
+	// shutdown, if any, and how far into it playback had gotten.
+	NowPlaying *TrackState   `json:"now_playing,omitempty"`
+	Position   time.Duration `json:"position"`
+
+	Queue []TrackState `json:"queue"`
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// registerApplicationCommands registers every command in commandRegistry as
+// a global Discord slash command and returns the resulting
+// ApplicationCommands, so they can be torn down again with -rmcmd.
+func registerApplicationCommands(s *discordgo.Session) ([]*discordgo.ApplicationCommand, error) {
+	registered := make([]*discordgo.ApplicationCommand, 0, len(commandRegistry))
+	for _, cmd := range commandRegistry {
+		ac, err := s.ApplicationCommandCreate(s.State.User.ID, "", &discordgo.ApplicationCommand{
+			Name:        cmd.Name(),
+			Description: cmd.Description(),
+			Options:     cmd.Options(),
+		})
+		if err != nil {
+			return registered, err
+		}
+		registered = append(registered, ac)
+	}
+	return registered, nil
+}
+
+// unregisterApplicationCommands removes every command previously returned by
+// registerApplicationCommands.
+func unregisterApplicationCommands(s *discordgo.Session, registered []*discordgo.ApplicationCommand) {
+	for _, ac := range registered {
+		if err := s.ApplicationCommandDelete(s.State.User.ID, "", ac.ID); err != nil {
+			fmt.Println("Failed to delete slash command", ac.Name+":", err)
+		}
+	}
+}
+
+// interactionCreate routes slash command invocations to the same Command
+// implementations used for prefix commands.
+func interactionCreate(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i.Type != discordgo.InteractionApplicationCommand {
+		return
+	}
+
+	data := i.ApplicationCommandData()
+	cmd, ok := commandsByName[data.Name]
+	if !ok {
+		return
+	}
+
+	g, err := s.State.Guild(i.GuildID)
+	if err != nil {
+		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: "This bot only works in guilds (servers).",
+			},
+		})
+		return
+	}
+
+	var c *Client
+	mClients.Lock()
+	{
+		var ok bool
+		if c, ok = clients[i.GuildID]; !ok {
+			c = NewClient(s)
+			c.GuildID = i.GuildID
+			clients[i.GuildID] = c
+		}
+	}
+	mClients.Unlock()
+	// Update the text and voice channels associated with the client, the
+	// same way messageCreate does via UpdateChannels.
+	c.UpdateInteractionChannels(g, i.Interaction)
+
+	if err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+	}); err != nil {
+		return
+	}
+
+	// Route Messagef to a followup on this interaction for the duration of
+	// the command, instead of the guild's text channel.
+	c.SetInteraction(i.Interaction)
+	cmd.Run(&CommandContext{Session: s, Guild: g, UserID: i.Member.User.ID}, c, optionArgs(data.Options))
+	c.SetInteraction(nil)
+}
+
+// optionArgs converts a slash command's typed options into the same []string
+// args shape prefix commands parse out of the message content, so both can
+// share a single Run implementation.
+func optionArgs(opts []*discordgo.ApplicationCommandInteractionDataOption) []string {
+	args := make([]string, len(opts))
+	for i, o := range opts {
+		if o.Type == discordgo.ApplicationCommandOptionInteger {
+			args[i] = strconv.FormatInt(o.IntValue(), 10)
+		} else {
+			args[i] = o.StringValue()
+		}
+	}
+	return args
+}
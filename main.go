@@ -1,13 +1,18 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
 	"os/signal"
 	"sync"
 	"syscall"
+	"time"
 
 	"dcbot/dca0"
+	"dcbot/guild"
+	"dcbot/source"
+	"dcbot/storage"
 	"dcbot/util"
 
 	"github.com/bwmarrin/discordgo"
@@ -33,16 +38,21 @@ func GetUserVoiceChannel(g *discordgo.Guild, userID string) (string, bool) {
 ////////////////////////////////
 type Playback struct {
 	Track
-	CmdCh  chan dca0.Command
-	RespCh chan dca0.Response
-	Paused bool
-	Loop   bool // Whether playback is looping right now.
+	CmdCh    chan dca0.Command
+	RespCh   chan dca0.Response
+	Paused   bool
+	Loop     bool          // Whether playback is looping right now.
+	Position time.Duration // Current position, as last reported by dca0.
 }
 
 type Track struct {
-	Title    string // Title, if any.
-	Url      string // Short URL, for example from YouTube.
-	MediaUrl string // Long URL of the associated media file.
+	Title       string        // Title, if any.
+	Url         string        // Short URL, for example from YouTube.
+	MediaUrl    string        // Long URL of the associated media file.
+	RequesterID string        // ID of the user who requested this track.
+	Duration    time.Duration // Track duration, if known.
+	Thumbnail   string        // Thumbnail image URL, if any.
+	Uploader    string        // Name of the uploader/artist, if known.
 }
 
 // All methods of Client are thread safe, however manual locking is required
@@ -53,6 +63,13 @@ type Client struct {
 	// The discordgo session.
 	s *discordgo.Session
 
+	// GuildID is the guild this client belongs to. Used as the storage key
+	// for persisting its state. May be "" for a client that is never
+	// persisted.
+	GuildID string
+	// Config holds this guild's overrides of the global configuration.
+	Config guild.Config
+
 	// TextChannelID and VoiceChannelID indicate the current channels through
 	// which the bot should send text / audio. They may be set to "".
 	TextChannelID  string
@@ -62,6 +79,19 @@ type Client struct {
 	Playback *Playback
 	// Queue.
 	Queue []*Track
+	// Vote currently in progress for this guild, if any (e.g. a skip or stop
+	// vote started via !voteskip).
+	Vote *VoteHolder
+
+	// Interaction is set for the duration of a slash command invocation, so
+	// that Messagef can route its output to an interaction followup instead
+	// of TextChannelID. It is nil for prefix-invoked commands.
+	Interaction *discordgo.Interaction
+
+	// nowPlayingMsgID is the ID of the last "Now Playing" embed sent to
+	// TextChannelID, so SendNowPlaying can edit it in place as the track
+	// advances instead of sending a new message every time.
+	nowPlayingMsgID string
 }
 
 func NewClient(s *discordgo.Session) *Client {
@@ -70,14 +100,104 @@ func NewClient(s *discordgo.Session) *Client {
 	}
 }
 
-func (c *Client) Messagef(format string, a ...interface{}) {
+// EffectivePrefix returns this guild's command prefix override, falling back
+// to the global default if none is set.
+func (c *Client) EffectivePrefix() string {
 	c.RLock()
-	if c.TextChannelID == "" {
-		fmt.Printf(format+"\n", a...)
-	} else {
-		c.s.ChannelMessageSend(c.TextChannelID, fmt.Sprintf(format, a...))
+	p := c.Config.Prefix
+	c.RUnlock()
+	if p == "" {
+		return cfg.Prefix
+	}
+	return p
+}
+
+// DJRoleID returns this guild's configured DJ role ID, or "" if none is
+// set.
+func (c *Client) DJRoleID() string {
+	c.RLock()
+	defer c.RUnlock()
+	return c.Config.DJRoleID
+}
+
+// Persist saves the subset of c's state needed to restore it after a
+// restart. It is a no-op if no storage backend is configured or c has no
+// GuildID.
+func (c *Client) Persist() {
+	if store == nil || c.GuildID == "" {
+		return
+	}
+
+	c.RLock()
+	state := guild.State{
+		Config:         c.Config,
+		TextChannelID:  c.TextChannelID,
+		VoiceChannelID: c.VoiceChannelID,
+		Queue:          make([]guild.TrackState, len(c.Queue)),
+	}
+	for i, t := range c.Queue {
+		state.Queue[i] = trackState(*t)
+	}
+	if c.Playback != nil {
+		state.Loop = c.Playback.Loop
+		state.Position = c.Playback.Position
+		ts := trackState(c.Playback.Track)
+		state.NowPlaying = &ts
 	}
 	c.RUnlock()
+
+	if err := store.Put(c.GuildID, &state); err != nil {
+		fmt.Println("Failed to persist state for guild", c.GuildID+":", err)
+	}
+}
+
+func trackState(t Track) guild.TrackState {
+	return guild.TrackState{
+		Title:       t.Title,
+		Url:         t.Url,
+		MediaUrl:    t.MediaUrl,
+		RequesterID: t.RequesterID,
+		Duration:    t.Duration,
+		Thumbnail:   t.Thumbnail,
+		Uploader:    t.Uploader,
+	}
+}
+
+func trackFromState(t guild.TrackState) *Track {
+	return &Track{
+		Title:       t.Title,
+		Url:         t.Url,
+		MediaUrl:    t.MediaUrl,
+		RequesterID: t.RequesterID,
+		Duration:    t.Duration,
+		Thumbnail:   t.Thumbnail,
+		Uploader:    t.Uploader,
+	}
+}
+
+func (c *Client) Messagef(format string, a ...interface{}) {
+	c.RLock()
+	i := c.Interaction
+	textChannelID := c.TextChannelID
+	c.RUnlock()
+
+	msg := fmt.Sprintf(format, a...)
+	switch {
+	case i != nil:
+		c.s.FollowupMessageCreate(i, true, &discordgo.WebhookParams{Content: msg})
+	case textChannelID == "":
+		fmt.Println(msg)
+	default:
+		c.s.ChannelMessageSend(textChannelID, msg)
+	}
+}
+
+// SetInteraction sets or clears the interaction Messagef routes its output
+// to. See the Interaction field's doc comment.
+func (c *Client) SetInteraction(i *discordgo.Interaction) {
+	c.Lock()
+	c.Interaction = i
+	c.Unlock()
 }
 
 // Updates the text channel and voice channel IDs. May set them to "" if there
@@ -89,6 +209,20 @@ func (c *Client) UpdateChannels(g *discordgo.Guild, m *discordgo.Message) {
 	vc, _ := GetUserVoiceChannel(g, m.Author.ID)
 	c.VoiceChannelID = vc
 	c.Unlock()
+	c.Persist()
+}
+
+// UpdateInteractionChannels is UpdateChannels' equivalent for slash command
+// invocations, which carry the invoking channel/user on the interaction
+// itself rather than on a discordgo.Message.
+func (c *Client) UpdateInteractionChannels(g *discordgo.Guild, i *discordgo.Interaction) {
+	c.Lock()
+	c.TextChannelID = i.ChannelID
+
+	vc, _ := GetUserVoiceChannel(g, i.Member.User.ID)
+	c.VoiceChannelID = vc
+	c.Unlock()
+	c.Persist()
 }
 
 func (c *Client) GetTextChannelID() string {
@@ -100,7 +234,7 @@ func (c *Client) GetTextChannelID() string {
 
 func (c *Client) GetVoiceChannelID() string {
 	c.RLock()
-	ret := c.TextChannelID
+	ret := c.VoiceChannelID
 	c.RUnlock()
 	return ret
 }
@@ -118,6 +252,23 @@ func (c *Client) GetPlaybackInfo() (p Playback, ok bool) {
 	}
 }
 
+// GetVote returns the vote currently in progress for this guild, or nil if
+// there isn't one.
+func (c *Client) GetVote() *VoteHolder {
+	c.RLock()
+	v := c.Vote
+	c.RUnlock()
+	return v
+}
+
+// SetVote replaces the vote currently in progress for this guild. Pass nil to
+// clear it once the vote has passed or expired.
+func (c *Client) SetVote(v *VoteHolder) {
+	c.Lock()
+	c.Vote = v
+	c.Unlock()
+}
+
 func (c *Client) QueueLen() int {
 	c.RLock()
 	l := len(c.Queue)
@@ -143,12 +294,14 @@ func (c *Client) QueuePushBack(t *Track) {
 	c.Lock()
 	c.Queue = append(c.Queue, t)
 	c.Unlock()
+	c.Persist()
 }
 
 func (c *Client) QueuePushFront(t *Track) {
 	c.Lock()
 	c.Queue = append([]*Track{t}, c.Queue...)
 	c.Unlock()
+	c.Persist()
 }
 
 func (c *Client) QueuePopFront() (t Track, ok bool) {
@@ -157,6 +310,7 @@ func (c *Client) QueuePopFront() (t Track, ok bool) {
 		c.Lock()
 		c.Queue = c.Queue[1:]
 		c.Unlock()
+		c.Persist()
 	}
 	return t, ok
 }
@@ -170,6 +324,7 @@ func (c *Client) QueueDelete(i int) bool {
 	c.Lock()
 	c.Queue = append(c.Queue[:i], c.Queue[i+1:]...)
 	c.Unlock()
+	c.Persist()
 	return true
 }
 
@@ -186,6 +341,7 @@ func (c *Client) QueueSwap(a, b int) bool {
 	c.Lock()
 	c.Queue[a], c.Queue[b] = c.Queue[b], c.Queue[a]
 	c.Unlock()
+	c.Persist()
 	return true
 }
 
@@ -193,6 +349,7 @@ func (c *Client) QueueClear() {
 	c.Lock()
 	c.Queue = nil
 	c.Unlock()
+	c.Persist()
 }
 
 func (c *Client) QueueFront() (t Track, ok bool) {
@@ -213,10 +370,17 @@ var mClients sync.Mutex
 
 var cfg Config
 
+// store persists guild state across restarts. It is nil until main opens the
+// storage backend; main exits if opening it fails.
+var store storage.Storage
+
 ////////////////////////////////
 // Main program.
 ////////////////////////////////
 func main() {
+	rmCmd := flag.Bool("rmcmd", false, "unregister all slash commands on shutdown instead of leaving them registered")
+	flag.Parse()
+
 	if err := ReadConfig(&cfg); err != nil {
 		fmt.Println(err)
 		if err := WriteDefaultConfig(); err != nil {
@@ -233,20 +397,33 @@ func main() {
 		return
 	}
 
-	// Check if all binary dependencies are installed correctly.
+	// Check if all binary dependencies are installed correctly. yt-dlp and
+	// SoundCloud extraction happen in-process via the source package, so only
+	// ffmpeg (used to pipe extracted audio into dca0) is still required.
 	const notInstalledErrMsg = "Unable to find %s in the specified path '%s', please make sure it's installed correctly.\nYou can manually set its path by editing %s\n"
-	if !util.CheckInstalled(cfg.YtdlPath, "--version") {
-		fmt.Printf(notInstalledErrMsg, "youtube-dl", cfg.YtdlPath, configFile)
-		return
-	}
 	if !util.CheckInstalled(cfg.FfmpegPath, "-version") {
 		fmt.Printf(notInstalledErrMsg, "ffmpeg", cfg.FfmpegPath, configFile)
 		return
 	}
 
+	// Register the track sources commandPlayTrack/commandAdd probe by URL.
+	source.Register(source.YouTube{})
+	if cfg.SoundCloudClientID != "" {
+		source.Register(source.NewSoundCloud(cfg.SoundCloudClientID))
+	}
+
 	// Initialize client map.
 	clients = make(map[string]*Client)
 
+	// Open the storage backend used to persist guild state across restarts.
+	st, err := storage.NewBoltStorage(cfg.StoragePath)
+	if err != nil {
+		fmt.Println("Unable to open storage database:", err)
+		return
+	}
+	store = st
+	defer store.Close()
+
 	// Initialize bot.
 	dg, err := discordgo.New("Bot " + cfg.Token)
 	if err != nil {
@@ -257,6 +434,7 @@ func main() {
 	dg.AddHandler(ready)
 	dg.AddHandler(banAdd)
 	dg.AddHandler(messageCreate)
+	dg.AddHandler(interactionCreate)
 
 	// What information we need about guilds.
 	dg.Identify.Intents = discordgo.IntentsGuilds | discordgo.IntentsGuildMessages | discordgo.IntentsGuildVoiceStates | discordgo.IntentsGuildBans
@@ -268,6 +446,12 @@ func main() {
 		return
 	}
 
+	// Register slash command equivalents of every prefix command.
+	registeredCommands, err := registerApplicationCommands(dg)
+	if err != nil {
+		fmt.Println("Failed to register slash commands:", err)
+	}
+
 	// Wait here until Ctrl+c or other term signal is received.
 	fmt.Println("Bot is now running. Press Ctrl+c to exit.")
 	sc := make(chan os.Signal, 1)
@@ -276,6 +460,14 @@ func main() {
 
 	fmt.Println("\nSignal received, closing Discord session.")
 
+	// Let every guild's worker finish the tracks already queued up rather
+	// than cutting them off mid-playback.
+	drainQueues()
+
+	if *rmCmd {
+		unregisterApplicationCommands(dg, registeredCommands)
+	}
+
 	// Cleanly close down the Discord session.
 	dg.Close()
 }
@@ -284,6 +476,51 @@ func ready(s *discordgo.Session, event *discordgo.Ready) {
 	u := s.State.User
 	fmt.Println("Logged in as", u.Username+"#"+u.Discriminator+".")
 	s.UpdateListeningStatus(cfg.Prefix + "help")
+
+	for _, g := range event.Guilds {
+		rehydrateClient(s, g.ID)
+	}
+}
+
+// rehydrateClient restores a guild's persisted state (if any) into a fresh
+// Client and resumes the interrupted track at its saved position. The
+// persisted loop flag is not restored; the resumed track plays with looping
+// off.
+func rehydrateClient(s *discordgo.Session, guildID string) {
+	var state guild.State
+	ok, err := store.Get(guildID, &state)
+	if err != nil {
+		fmt.Println("Failed to restore state for guild", guildID+":", err)
+		return
+	}
+	if !ok {
+		return
+	}
+
+	c := NewClient(s)
+	c.GuildID = guildID
+	c.Config = state.Config
+	c.TextChannelID = state.TextChannelID
+	c.VoiceChannelID = state.VoiceChannelID
+	for _, t := range state.Queue {
+		c.QueuePushBack(trackFromState(t))
+	}
+
+	mClients.Lock()
+	clients[guildID] = c
+	mClients.Unlock()
+
+	if state.NowPlaying != nil && state.VoiceChannelID != "" {
+		t := trackFromState(*state.NowPlaying)
+		c.Messagef("Resuming %s at %s after restart.", t.Title, state.Position)
+		Enqueue(s, &Play{
+			GuildID:   guildID,
+			ChannelID: state.VoiceChannelID,
+			UserID:    t.RequesterID,
+			Track:     *t,
+			SeekTo:    state.Position,
+		})
+	}
 }
 
 func banAdd(s *discordgo.Session, event *discordgo.GuildBanAdd) {
@@ -310,6 +547,7 @@ func messageCreate(s *discordgo.Session, m *discordgo.MessageCreate) {
 		var ok bool
 		if c, ok = clients[m.GuildID]; !ok {
 			c = NewClient(s)
+			c.GuildID = m.GuildID
 			clients[m.GuildID] = c
 		}
 	}
@@ -317,43 +555,20 @@ func messageCreate(s *discordgo.Session, m *discordgo.MessageCreate) {
 	// Update the text and voice channels associated with the client.
 	c.UpdateChannels(g, m.Message)
 
-	args, ok := CmdGetArgs(m.Content)
+	args, ok := CmdGetArgs(m.Content, c.EffectivePrefix())
 	if !ok {
 		// Not a command.
 		return
 	}
 
 	if len(args) == 0 {
-		c.Messagef("No command specified. Type `%shelp` for help.", cfg.Prefix)
+		c.Messagef("No command specified. Type `%shelp` for help.", c.EffectivePrefix())
 		return
 	}
 
-	switch args[0] {
-	case "help":
-		commandHelp(c)
-	case "play":
-		commandPlay(s, g, c, args[1:])
-	case "seek":
-		commandSeek(c, args[1:])
-	case "pos":
-		commandPos(c)
-	case "loop":
-		commandLoop(c)
-	case "add":
-		commandAdd(c, args[1:], false)
-	case "queue":
-		commandQueue(c)
-	case "pause":
-		commandPause(c)
-	case "stop":
-		commandStop(c)
-	case "skip":
-		commandSkip(c)
-	case "delete":
-		commandDelete(c, args[1:])
-	case "swap":
-		commandSwap(c, args[1:])
-	case "shuffle":
-		commandShuffle(c)
+	cmd, ok := commandsByName[args[0]]
+	if !ok {
+		return
 	}
+	cmd.Run(&CommandContext{Session: s, Guild: g, UserID: m.Author.ID}, c, args[1:])
 }
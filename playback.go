@@ -0,0 +1,25 @@
+package main
+
+// watchProgress keeps c.Playback.Position up to date with the progress
+// dca0 reports on p.RespCh, for as long as p remains c's current Playback.
+// It returns once p.RespCh is closed (playback for p has ended) or p has
+// been replaced by a different Playback.
+//
+// Started by whatever begins playback (commandPlayTrack), so that
+// Persist and the Now Playing embed reflect how far into the track
+// playback has actually gotten, rather than staying stuck at 0.
+func watchProgress(c *Client, p *Playback) {
+	for resp := range p.RespCh {
+		c.Lock()
+		current := c.Playback == p
+		if current {
+			c.Playback.Position = resp.Position
+		}
+		c.Unlock()
+
+		if !current {
+			return
+		}
+		c.Persist()
+	}
+}
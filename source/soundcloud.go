@@ -0,0 +1,52 @@
+package source
+
+import (
+	"io"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/Depado/soundcloud"
+)
+
+var soundcloudUrlRegexp = regexp.MustCompile(`^https?://(www\.)?soundcloud\.com/`)
+
+// SoundCloud resolves soundcloud.com track URLs via the SoundCloud API.
+type SoundCloud struct {
+	client *soundcloud.Client
+}
+
+// NewSoundCloud creates a SoundCloud source authenticated with the given
+// API client ID.
+func NewSoundCloud(clientID string) *SoundCloud {
+	return &SoundCloud{client: soundcloud.New(clientID)}
+}
+
+func (s *SoundCloud) CanResolve(url string) bool {
+	return soundcloudUrlRegexp.MatchString(url)
+}
+
+func (s *SoundCloud) Resolve(url string) (Track, io.ReadCloser, error) {
+	t, err := s.client.GetTrackInfo(soundcloud.GetTrackInfoParams{URL: url})
+	if err != nil {
+		return Track{}, nil, err
+	}
+
+	streamUrl, err := s.client.GetStreamURL(t)
+	if err != nil {
+		return Track{}, nil, err
+	}
+
+	resp, err := http.Get(streamUrl)
+	if err != nil {
+		return Track{}, nil, err
+	}
+
+	return Track{
+		Title:     t.Title,
+		Url:       url,
+		Uploader:  t.User.Username,
+		Duration:  time.Duration(t.Duration) * time.Millisecond,
+		Thumbnail: t.ArtworkURL,
+	}, resp.Body, nil
+}
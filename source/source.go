@@ -0,0 +1,47 @@
+// Package source resolves a URL into streamable track audio, abstracting
+// over the different services the bot can play from.
+package source
+
+import (
+	"io"
+	"time"
+)
+
+// Track is the metadata a Source extracts about a track before streaming
+// it.
+type Track struct {
+	Title     string
+	Url       string
+	Uploader  string
+	Duration  time.Duration
+	Thumbnail string
+}
+
+// Source resolves a URL into a Track and a stream of its best-quality audio.
+// The caller is responsible for closing the returned io.ReadCloser.
+type Source interface {
+	// CanResolve reports whether url belongs to this source.
+	CanResolve(url string) bool
+	Resolve(url string) (Track, io.ReadCloser, error)
+}
+
+// sources is the set of Sources probed by Resolve, in registration order.
+var sources []Source
+
+// Register adds src to the set of sources probed by Resolve.
+func Register(src Source) {
+	sources = append(sources, src)
+}
+
+// Resolve finds the first registered Source able to handle url and uses it
+// to resolve the track. ok is false if no registered source recognizes url.
+func Resolve(url string) (t Track, stream io.ReadCloser, ok bool, err error) {
+	for _, src := range sources {
+		if !src.CanResolve(url) {
+			continue
+		}
+		t, stream, err = src.Resolve(url)
+		return t, stream, true, err
+	}
+	return Track{}, nil, false, nil
+}
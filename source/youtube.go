@@ -0,0 +1,41 @@
+package source
+
+import (
+	"context"
+	"io"
+	"regexp"
+	"time"
+
+	"github.com/wader/goutubedl"
+)
+
+var youtubeUrlRegexp = regexp.MustCompile(`^https?://(www\.)?(youtube\.com|youtu\.be)/`)
+
+// YouTube resolves YouTube URLs via yt-dlp's JSON API (through goutubedl),
+// streaming the best available audio format directly rather than shelling
+// out to a separate youtube-dl/yt-dlp process per track.
+type YouTube struct{}
+
+func (YouTube) CanResolve(url string) bool {
+	return youtubeUrlRegexp.MatchString(url)
+}
+
+func (YouTube) Resolve(url string) (Track, io.ReadCloser, error) {
+	result, err := goutubedl.New(context.Background(), url, goutubedl.Options{})
+	if err != nil {
+		return Track{}, nil, err
+	}
+
+	dl, err := result.Download(context.Background(), "bestaudio")
+	if err != nil {
+		return Track{}, nil, err
+	}
+
+	return Track{
+		Title:     result.Info.Title,
+		Url:       url,
+		Uploader:  result.Info.Uploader,
+		Duration:  time.Duration(result.Info.Duration * float64(time.Second)),
+		Thumbnail: result.Info.Thumbnail,
+	}, dl, nil
+}
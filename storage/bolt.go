@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"encoding/json"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var guildsBucket = []byte("guilds")
+
+// BoltStorage is a Storage backed by a local BoltDB file.
+type BoltStorage struct {
+	db *bolt.DB
+}
+
+// NewBoltStorage opens the BoltDB file at path, creating it (and the bucket
+// used to hold guild state) if it doesn't exist yet.
+func NewBoltStorage(path string) (*BoltStorage, error) {
+	db, err := bolt.Open(path, 0666, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(guildsBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &BoltStorage{db: db}, nil
+}
+
+func (b *BoltStorage) Get(guildID string, v interface{}) (ok bool, err error) {
+	err = b.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(guildsBucket).Get([]byte(guildID))
+		if data == nil {
+			return nil
+		}
+		ok = true
+		return json.Unmarshal(data, v)
+	})
+	return ok, err
+}
+
+func (b *BoltStorage) Put(guildID string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(guildsBucket).Put([]byte(guildID), data)
+	})
+}
+
+func (b *BoltStorage) Delete(guildID string) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(guildsBucket).Delete([]byte(guildID))
+	})
+}
+
+func (b *BoltStorage) Close() error {
+	return b.db.Close()
+}
@@ -0,0 +1,16 @@
+// Package storage persists per-guild bot state so it survives restarts.
+package storage
+
+// Storage is a key-value store keyed by Discord guild ID.
+type Storage interface {
+	// Get unmarshals the value stored for guildID into v. ok is false if
+	// nothing is stored for guildID yet, in which case v is left untouched.
+	Get(guildID string, v interface{}) (ok bool, err error)
+	// Put marshals v and stores it for guildID, overwriting any previous
+	// value.
+	Put(guildID string, v interface{}) error
+	// Delete removes the stored value for guildID, if any.
+	Delete(guildID string) error
+	// Close releases any resources held by the store.
+	Close() error
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"dcbot/dca0"
+	"dcbot/source"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// resolveTrack looks t.Url up via source.Resolve, filling in t's metadata
+// (title, duration, thumbnail, uploader) from whichever registered source
+// recognizes it, and returns a stream of its audio. The caller is
+// responsible for closing the returned stream.
+func resolveTrack(t *Track) (io.ReadCloser, error) {
+	resolved, stream, ok, err := source.Resolve(t.Url)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("no source recognizes %s", t.Url)
+	}
+
+	t.Title = resolved.Title
+	t.Duration = resolved.Duration
+	t.Thumbnail = resolved.Thumbnail
+	t.Uploader = resolved.Uploader
+	return stream, nil
+}
+
+// commandPlayTrack resolves t via the source package and starts streaming
+// it into c's current voice channel, replacing whatever Playback was
+// running before. seekTo starts playback partway through, e.g. when
+// resuming a track interrupted by a restart.
+func commandPlayTrack(s *discordgo.Session, c *Client, t Track, seekTo time.Duration) {
+	stream, err := resolveTrack(&t)
+	if err != nil {
+		c.Messagef("Failed to resolve %s: %s", t.Url, err)
+		return
+	}
+
+	vc, err := s.ChannelVoiceJoin(c.GuildID, c.GetVoiceChannelID(), false, true)
+	if err != nil {
+		stream.Close()
+		c.Messagef("Failed to join the voice channel: %s", err)
+		return
+	}
+
+	cmdCh, respCh := dca0.Stream(vc, stream, cfg.FfmpegPath, seekTo)
+	p := &Playback{
+		Track:    t,
+		CmdCh:    cmdCh,
+		RespCh:   respCh,
+		Position: seekTo,
+	}
+
+	c.Lock()
+	c.Playback = p
+	c.Unlock()
+	c.Persist()
+
+	go watchProgress(c, p)
+
+	c.SendNowPlaying(t)
+}
+
+// commandAdd resolves query via the source package and pushes the result
+// onto the queue without starting playback; it plays once commandPlayTrack
+// reaches it via the worker. next pushes to the front of the queue instead
+// of the back.
+func commandAdd(c *Client, args []string, next bool) {
+	if len(args) == 0 {
+		c.Messagef("Usage: %sadd <url>", c.EffectivePrefix())
+		return
+	}
+
+	t := Track{Url: args[0]}
+	stream, err := resolveTrack(&t)
+	if err != nil {
+		c.Messagef("Failed to resolve %s: %s", t.Url, err)
+		return
+	}
+	// Only t's metadata is needed to queue it; the audio itself is
+	// re-resolved by commandPlayTrack once the track reaches the front of
+	// the queue, so the stream opened just now isn't kept around.
+	stream.Close()
+
+	if next {
+		c.QueuePushFront(&t)
+	} else {
+		c.QueuePushBack(&t)
+	}
+	c.Messagef("Added \"%s\" to the queue.", t.Title)
+}
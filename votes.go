@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Ratio of eligible (non-bot) voice channel members that must vote yes
+// before a vote-initiated skip or stop is carried out.
+const voteRatio = 0.5
+
+// How long a vote stays open before it expires.
+const voteTimeout = 30 * time.Second
+
+// VoteHolder tracks a single in-progress democratic vote (e.g. skip or stop)
+// for a guild. All of its methods are thread safe.
+type VoteHolder struct {
+	sync.Mutex
+
+	// Name of the action being voted on, e.g. "skip". Used in tally messages.
+	Name string
+	// ID of the user who requested the track currently playing. Their vote
+	// always passes the vote immediately.
+	RequesterID string
+	// IDs of the users who have voted yes so far.
+	Voters map[string]bool
+
+	timer *time.Timer
+}
+
+// NewVoteHolder creates a vote that automatically expires and calls onExpire
+// after voteTimeout unless it passes first.
+func NewVoteHolder(name, requesterID string, onExpire func()) *VoteHolder {
+	return &VoteHolder{
+		Name:        name,
+		RequesterID: requesterID,
+		Voters:      make(map[string]bool),
+		timer:       time.AfterFunc(voteTimeout, onExpire),
+	}
+}
+
+// Vote registers userID's yes vote. eligible is the number of non-bot
+// members currently in the relevant voice channel. It returns whether the
+// vote has now passed.
+func (v *VoteHolder) Vote(userID string, eligible int) (passed bool) {
+	v.Lock()
+	defer v.Unlock()
+
+	if v.Voters[userID] {
+		return false
+	}
+	v.Voters[userID] = true
+
+	if userID == v.RequesterID || float64(len(v.Voters)) >= float64(eligible)*voteRatio {
+		v.timer.Stop()
+		return true
+	}
+	return false
+}
+
+// Tally returns the running vote count formatted for display in chat, e.g.
+// "2/4".
+func (v *VoteHolder) Tally(eligible int) string {
+	v.Lock()
+	defer v.Unlock()
+	return fmt.Sprintf("%d/%d", len(v.Voters), eligible)
+}
+
+// Cancel stops the vote's timeout timer without running onExpire. Used when
+// the vote is being torn down for a reason other than expiry, e.g. a force
+// skip.
+func (v *VoteHolder) Cancel() {
+	v.Lock()
+	defer v.Unlock()
+	v.timer.Stop()
+}
+
+// countEligibleVoters returns the number of non-bot members currently
+// connected to voiceChannelID. It reads the bot flag off each VoiceState's
+// own Member rather than looking it up in g.Members, since the latter
+// requires the privileged IntentsGuildMembers intent the bot doesn't
+// request.
+func countEligibleVoters(g *discordgo.Guild, voiceChannelID string) int {
+	n := 0
+	for _, vs := range g.VoiceStates {
+		if vs.ChannelID != voiceChannelID || (vs.Member != nil && vs.Member.User.Bot) {
+			continue
+		}
+		n++
+	}
+	return n
+}
+
+// startVote either registers userID's vote against the vote already running
+// for c, or starts a new one if none is running yet. If a vote for a
+// different action is already running, userID's vote is rejected rather than
+// being mixed into the wrong tally.
+func startVote(g *discordgo.Guild, c *Client, userID, name string, action func(*Client)) {
+	voiceChannelID := c.GetVoiceChannelID()
+	eligible := countEligibleVoters(g, voiceChannelID)
+
+	v := c.GetVote()
+	if v != nil && v.Name != name {
+		c.Messagef("A vote to %s is already in progress; wait for it to finish before starting a vote to %s.", v.Name, name)
+		return
+	}
+	if v == nil {
+		requesterID := ""
+		if t, ok := c.GetPlaybackInfo(); ok {
+			requesterID = t.RequesterID
+		}
+		v = NewVoteHolder(name, requesterID, func() {
+			c.SetVote(nil)
+			c.Messagef("Vote to %s expired.", name)
+		})
+		c.SetVote(v)
+	}
+
+	if v.Vote(userID, eligible) {
+		c.SetVote(nil)
+		c.Messagef("Vote to %s passed (%s).", name, v.Tally(eligible))
+		action(c)
+	} else {
+		c.Messagef("Vote to %s: %s (need at least %.0f%% of %d members in the voice channel).", name, v.Tally(eligible), voteRatio*100, eligible)
+	}
+}
+
+func commandVoteSkip(g *discordgo.Guild, c *Client, userID string) {
+	startVote(g, c, userID, "skip", commandSkip)
+}
+
+func commandVoteStop(g *discordgo.Guild, c *Client, userID string) {
+	startVote(g, c, userID, "stop", commandStop)
+}
+
+// commandForceSkip lets a server admin, or a member of the configured DJ
+// role, immediately end the running vote (if any) and skip the current
+// track.
+func commandForceSkip(s *discordgo.Session, g *discordgo.Guild, c *Client, userID string) {
+	if !canForceSkip(s, g, c, userID) {
+		c.Messagef("Only server admins or the DJ role can force-skip.")
+		return
+	}
+
+	if v := c.GetVote(); v != nil {
+		v.Cancel()
+		c.SetVote(nil)
+	}
+	commandSkip(c)
+}
+
+// canForceSkip reports whether userID may bypass the vote and force-skip
+// directly, either as a server admin or as a member of c's configured DJ
+// role (guild.Config.DJRoleID).
+func canForceSkip(s *discordgo.Session, g *discordgo.Guild, c *Client, userID string) bool {
+	perms, err := s.State.UserChannelPermissions(userID, c.GetTextChannelID())
+	if err == nil && perms&discordgo.PermissionAdministrator != 0 {
+		return true
+	}
+
+	djRoleID := c.DJRoleID()
+	if djRoleID == "" {
+		return false
+	}
+	member, err := s.State.Member(g.ID, userID)
+	if err != nil {
+		return false
+	}
+	for _, r := range member.Roles {
+		if r == djRoleID {
+			return true
+		}
+	}
+	return false
+}
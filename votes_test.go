@@ -0,0 +1,66 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestVoteHolderQuorum(t *testing.T) {
+	const eligible = 4 // quorum at voteRatio (0.5) is 2 yes votes.
+
+	v := NewVoteHolder("skip", "requester", func() {})
+
+	if passed := v.Vote("voter-a", eligible); passed {
+		t.Fatalf("vote passed after a single non-requester vote with %d eligible voters", eligible)
+	}
+	if passed := v.Vote("voter-a", eligible); passed {
+		t.Fatalf("re-voting with the same user must not count twice or pass the vote")
+	}
+	if passed := v.Vote("voter-b", eligible); !passed {
+		t.Fatalf("vote should have passed once quorum (%.0f%% of %d) was reached", voteRatio*100, eligible)
+	}
+}
+
+func TestVoteHolderRequesterAutoPasses(t *testing.T) {
+	v := NewVoteHolder("stop", "requester", func() {})
+
+	if passed := v.Vote("requester", 10); !passed {
+		t.Fatalf("the track requester's vote should pass the vote immediately regardless of quorum")
+	}
+}
+
+// TestStartVoteRejectsDifferentAction exercises startVote itself (not just
+// VoteHolder.Vote) to make sure a votestop can't be folded into an
+// in-progress voteskip's tally.
+func TestStartVoteRejectsDifferentAction(t *testing.T) {
+	g := &discordgo.Guild{
+		VoiceStates: []*discordgo.VoiceState{
+			{ChannelID: "voice", UserID: "user-a", Member: &discordgo.Member{User: &discordgo.User{ID: "user-a"}}},
+			{ChannelID: "voice", UserID: "user-b", Member: &discordgo.Member{User: &discordgo.User{ID: "user-b"}}},
+			{ChannelID: "voice", UserID: "user-c", Member: &discordgo.Member{User: &discordgo.User{ID: "user-c"}}},
+			{ChannelID: "voice", UserID: "user-d", Member: &discordgo.Member{User: &discordgo.User{ID: "user-d"}}},
+		},
+	}
+	c := NewClient(nil)
+	c.VoiceChannelID = "voice"
+
+	startVote(g, c, "user-a", "skip", func(*Client) {
+		t.Fatalf("voteskip should not have passed with a single voter out of 4 eligible")
+	})
+
+	startVote(g, c, "user-b", "stop", func(*Client) {
+		t.Fatalf("votestop must not run its action by being folded into the voteskip's tally")
+	})
+
+	v := c.GetVote()
+	if v == nil {
+		t.Fatalf("the in-progress voteskip must not have been cleared by the rejected votestop")
+	}
+	if v.Name != "skip" {
+		t.Fatalf("votestop must not replace the in-progress voteskip, got a vote for %q", v.Name)
+	}
+	if len(v.Voters) != 1 {
+		t.Fatalf("votestop's vote must not be counted toward the voteskip's tally, got %d voters", len(v.Voters))
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// maxQueueSize bounds how many pending Plays a single guild's worker queue
+// may buffer before Enqueue blocks, so a noisy guild can never grow without
+// bound or starve others of memory.
+const maxQueueSize = 16
+
+// Play is a single unit of work for a guild's worker goroutine: join
+// ChannelID and play Track, requested by UserID. Next chains another Play
+// to run immediately afterwards on the same worker, e.g. an intro jingle
+// queued ahead of the track the user actually requested.
+type Play struct {
+	GuildID   string
+	ChannelID string
+	UserID    string
+	Track     Track
+	// SeekTo is the position playback should start at, e.g. when resuming a
+	// track interrupted by a restart. Zero means start from the beginning.
+	SeekTo time.Duration
+	Next   *Play
+}
+
+// guildQueue is one guild's bounded play queue and its worker's exit signal.
+type guildQueue struct {
+	ch   chan *Play
+	done chan struct{}
+}
+
+// queues holds one guildQueue per guild with a running worker. Guild ID to
+// queue. mQueues also guards draining and must be held while checking it
+// and incrementing sending, so Enqueue and drainQueues can never race on
+// the same channel (see Enqueue and drainQueues).
+var queues = make(map[string]*guildQueue)
+var mQueues sync.Mutex
+var draining bool
+var sending sync.WaitGroup
+
+// getQueue returns guildID's play queue, starting its worker goroutine the
+// first time it's requested. Callers must hold mQueues.
+func getQueue(s *discordgo.Session, guildID string) *guildQueue {
+	if q, ok := queues[guildID]; ok {
+		return q
+	}
+
+	q := &guildQueue{
+		ch:   make(chan *Play, maxQueueSize),
+		done: make(chan struct{}),
+	}
+	queues[guildID] = q
+	go worker(s, q)
+	return q
+}
+
+// worker drains a single guild's play queue one Play (and its chained Next
+// Plays) at a time, so voice-connection joins/leaves for that guild are
+// always serialized and a noisy guild can never block another guild's
+// playback.
+func worker(s *discordgo.Session, q *guildQueue) {
+	defer close(q.done)
+	for p := range q.ch {
+		for p != nil {
+			playOne(s, p)
+			p = p.Next
+		}
+	}
+}
+
+// playOne moves c's voice channel to p.ChannelID and plays p.Track.
+func playOne(s *discordgo.Session, p *Play) {
+	mClients.Lock()
+	c, ok := clients[p.GuildID]
+	mClients.Unlock()
+	if !ok {
+		return
+	}
+
+	c.Lock()
+	c.VoiceChannelID = p.ChannelID
+	c.Unlock()
+
+	commandPlayTrack(s, c, p.Track, p.SeekTo)
+}
+
+// Enqueue submits p onto its guild's play queue, starting the worker if
+// necessary, and returns as soon as p is buffered; p plays asynchronously.
+// If the queue already holds maxQueueSize Plays, Enqueue blocks until the
+// worker takes one. Enqueue is a
+// no-op once drainQueues has started, since by then every queue's channel
+// is being (or about to be) closed.
+func Enqueue(s *discordgo.Session, p *Play) {
+	mQueues.Lock()
+	if draining {
+		mQueues.Unlock()
+		return
+	}
+	q := getQueue(s, p.GuildID)
+	sending.Add(1)
+	mQueues.Unlock()
+	defer sending.Done()
+
+	q.ch <- p
+}
+
+// drainQueues closes every guild's play queue and blocks until each
+// worker has finished the Plays already buffered in it. Call before
+// dg.Close() on shutdown so an in-flight track isn't cut off mid-playback.
+//
+// It first blocks new Enqueue calls and waits for any send already in
+// flight to finish, so closing q.ch here can never race with Enqueue
+// sending on it.
+func drainQueues() {
+	mQueues.Lock()
+	draining = true
+	qs := make([]*guildQueue, 0, len(queues))
+	for _, q := range queues {
+		qs = append(qs, q)
+	}
+	mQueues.Unlock()
+
+	sending.Wait()
+
+	for _, q := range qs {
+		close(q.ch)
+	}
+	for _, q := range qs {
+		<-q.done
+	}
+}